@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var processedBucket = []byte("processed")
+
+// boltCache stores ProcessedRecords in a bbolt database file, so the
+// Scheduler's dedup cache survives restarts of the daemon.
+type boltCache struct {
+	db *bolt.DB
+}
+
+func openBoltCache(path string) (*boltCache, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening cache %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(processedBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing cache %s: %w", path, err)
+	}
+	return &boltCache{db: db}, nil
+}
+
+func (c *boltCache) Get(path string) (ProcessedRecord, bool, error) {
+	var record ProcessedRecord
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(processedBucket).Get([]byte(path))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &record)
+	})
+	return record, found, err
+}
+
+func (c *boltCache) Put(record ProcessedRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(processedBucket).Put([]byte(record.Path), data)
+	})
+}
+
+func (c *boltCache) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// options holds the gofmt-style CLI surface: a single input path (file or
+// directory), an output directory or in-place flag, and the knobs that feed
+// the pipeline.
+type options struct {
+	Input     string
+	Output    string
+	Recursive bool
+	Overwrite bool
+	Workers   int
+	Format    string
+	Quality   int
+	Mode      string
+	Filters   string
+	Progress  string
+
+	Daemon       bool
+	ScanInterval time.Duration
+	CachePath    string
+	HTTPAddr     string
+}
+
+func parseFlags(args []string) (options, error) {
+	fs := flag.NewFlagSet("image-processor", flag.ContinueOnError)
+
+	var o options
+	fs.StringVar(&o.Input, "i", "", "input file or directory (required)")
+	fs.StringVar(&o.Output, "o", "", "output directory (ignored with -w)")
+	fs.BoolVar(&o.Recursive, "r", false, "recurse into subdirectories of -i")
+	fs.BoolVar(&o.Overwrite, "w", false, "overwrite files in place instead of writing to -o")
+	fs.IntVar(&o.Workers, "workers", runtime.NumCPU(), "number of concurrent filter workers")
+	fs.StringVar(&o.Format, "format", "auto", "output format: jpeg, png, webp, tiff, or auto to preserve the input format")
+	fs.IntVar(&o.Quality, "quality", jpeg.DefaultQuality, "output quality for lossy formats (jpeg)")
+	fs.StringVar(&o.Mode, "mode", "parallel", "processing mode: sequential or parallel")
+	fs.StringVar(&o.Filters, "filters", "grayscale,sepia", "comma-separated filter chain to apply; a filter may take params after a colon, e.g. sauvola:k=0.5;w=25")
+	fs.StringVar(&o.Progress, "progress", "terminal", "progress reporting: terminal, json, or none")
+	fs.BoolVar(&o.Daemon, "daemon", false, "run as a long-lived service that watches -i instead of processing once")
+	fs.DurationVar(&o.ScanInterval, "scan-interval", 30*time.Second, "how often the daemon rescans -i for new/changed files")
+	fs.StringVar(&o.CachePath, "cache", ".image-processor-cache.db", "bbolt cache file the daemon uses to skip already-processed files")
+	fs.StringVar(&o.HTTPAddr, "http-addr", "127.0.0.1:8080", "address the daemon's /healthz, /stats, /reprocess endpoints listen on")
+
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+
+	if o.Input == "" {
+		return options{}, fmt.Errorf("-i is required")
+	}
+	if !o.Overwrite && o.Output == "" {
+		return options{}, fmt.Errorf("-o is required unless -w is set")
+	}
+	if o.Workers < 1 {
+		return options{}, fmt.Errorf("invalid -workers %d: must be at least 1", o.Workers)
+	}
+	switch o.Mode {
+	case "sequential", "parallel":
+	default:
+		return options{}, fmt.Errorf("invalid -mode %q: must be sequential or parallel", o.Mode)
+	}
+	switch o.Format {
+	case "auto", "jpeg", "png", "webp", "tiff":
+	default:
+		return options{}, fmt.Errorf("invalid -format %q: must be jpeg, png, webp, tiff, or auto", o.Format)
+	}
+	switch o.Progress {
+	case "terminal", "json", "none":
+	default:
+		return options{}, fmt.Errorf("invalid -progress %q: must be terminal, json, or none", o.Progress)
+	}
+
+	return o, nil
+}
+
+// newProgress builds the Progress implementation requested by -progress.
+func newProgress(o options) Progress {
+	switch o.Progress {
+	case "json":
+		return newJSONProgress()
+	case "none":
+		return noProgress{}
+	default:
+		return newTerminalProgress()
+	}
+}
+
+// collectInputFiles resolves -i to a concrete list of image files: itself if
+// it's a single file, or every file under it (optionally recursing into
+// subdirectories) if it's a directory.
+func collectInputFiles(o options) ([]string, error) {
+	info, err := os.Stat(o.Input)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", o.Input, err)
+	}
+	if !info.IsDir() {
+		return []string{o.Input}, nil
+	}
+
+	var files []string
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != o.Input && !o.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isSupportedExt(path) {
+			files = append(files, path)
+		}
+		return nil
+	}
+	if err := filepath.WalkDir(o.Input, walk); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func isSupportedExt(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".tif", ".tiff":
+		return true
+	default:
+		return false
+	}
+}
+
+// outputPathFor returns where a processed copy of src should be written,
+// honoring -w (in place) vs -o (a destination directory) and -format
+// (rewriting the extension, unless -format=auto keeps the original one).
+func outputPathFor(o options, src string) string {
+	base := filepath.Base(src)
+	if o.Format != "auto" {
+		base = strings.TrimSuffix(base, filepath.Ext(base)) + "." + extForFormat(o.Format)
+	}
+	if o.Overwrite {
+		return filepath.Join(filepath.Dir(src), base)
+	}
+	return filepath.Join(o.Output, base)
+}
+
+func extForFormat(format string) string {
+	switch format {
+	case "jpeg":
+		return "jpg"
+	default:
+		return format
+	}
+}
+
+// formatFor resolves -format=auto to the concrete format a source file
+// should be re-encoded as, based on its own extension.
+func formatFor(o options, src string) string {
+	if o.Format != "auto" {
+		return o.Format
+	}
+	switch strings.ToLower(filepath.Ext(src)) {
+	case ".png":
+		return "png"
+	case ".webp":
+		return "webp"
+	case ".tif", ".tiff":
+		return "tiff"
+	default:
+		return "jpeg"
+	}
+}
+
+// encodeImage writes img in the requested format. webp has no pure-Go
+// encoder in golang.org/x/image, so requesting it as an output format fails
+// with a clear error rather than silently falling back to another format.
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	case "tiff":
+		return tiff.Encode(w, img, nil)
+	case "webp":
+		return fmt.Errorf("webp encoding is not supported (golang.org/x/image/webp is decode-only)")
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
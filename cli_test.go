@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestParseFlagsRejectsNonPositiveWorkers(t *testing.T) {
+	for _, workers := range []string{"0", "-1"} {
+		_, err := parseFlags([]string{"-i", ".", "-w", "-workers", workers})
+		if err == nil {
+			t.Errorf("-workers %s: expected an error, got nil", workers)
+		}
+	}
+}
+
+func TestParseFlagsAcceptsPositiveWorkers(t *testing.T) {
+	o, err := parseFlags([]string{"-i", ".", "-w", "-workers", "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.Workers != 3 {
+		t.Errorf("Workers = %d, want 3", o.Workers)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// newDaemonMux wires the scheduler's HTTP surface: /healthz for liveness,
+// /stats for queue depth and per-stage metrics, and /reprocess to force a
+// specific path back through the pipeline regardless of the cache.
+func newDaemonMux(s *Scheduler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.Stats()); err != nil {
+			log.Printf("daemon: encoding /stats: %v", err)
+		}
+	})
+
+	mux.HandleFunc("/reprocess", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			http.Error(w, "missing path query parameter", http.StatusBadRequest)
+			return
+		}
+		if err := s.Reprocess(path); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errOutsideRoot) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		fmt.Fprintf(w, "reprocessed %s\n", path)
+	})
+
+	return mux
+}
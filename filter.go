@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Filter transforms one image into another. Implementations must be safe to
+// call concurrently from multiple goroutines since a single Filter instance
+// is shared by every worker in a pipeline stage.
+type Filter interface {
+	Apply(img image.Image) (image.Image, error)
+}
+
+type grayscaleFilter struct{}
+
+func (grayscaleFilter) Apply(img image.Image) (image.Image, error) {
+	return toGrayscale(img), nil
+}
+
+// ApplyRect is grayscaleFilter's tile fast-path: each output pixel only
+// depends on the input pixel at the same coordinates, so it needs no halo.
+func (grayscaleFilter) ApplyRect(dst *image.RGBA, src image.Image, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetRGBA(x, y, grayAt(src, x, y))
+		}
+	}
+}
+
+type sepiaFilter struct{}
+
+func (sepiaFilter) Apply(img image.Image) (image.Image, error) {
+	return toSepia(img), nil
+}
+
+// ApplyRect is sepiaFilter's tile fast-path; like grayscale, each output
+// pixel only depends on the input pixel at the same coordinates.
+func (sepiaFilter) ApplyRect(dst *image.RGBA, src image.Image, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetRGBA(x, y, sepiaAt(src, x, y))
+		}
+	}
+}
+
+// grayAt converts the pixel at (x,y) to its grayscale RGBA equivalent.
+func grayAt(src image.Image, x, y int) color.RGBA {
+	r, g, b, a := src.At(x, y).RGBA()
+	gray := uint8((0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 256)
+	return color.RGBA{R: gray, G: gray, B: gray, A: uint8(a / 256)}
+}
+
+// sepiaAt converts the pixel at (x,y) to its sepia-toned RGBA equivalent.
+func sepiaAt(src image.Image, x, y int) color.RGBA {
+	r, g, b, a := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA).RGBA()
+
+	r8, g8, b8 := uint8(r/257), uint8(g/257), uint8(b/257)
+
+	newR := float64(r8)*0.393 + float64(g8)*0.769 + float64(b8)*0.189
+	newG := float64(r8)*0.349 + float64(g8)*0.686 + float64(b8)*0.168
+	newB := float64(r8)*0.272 + float64(g8)*0.534 + float64(b8)*0.131
+
+	return color.RGBA{
+		R: uint8(min(255, int(newR))),
+		G: uint8(min(255, int(newG))),
+		B: uint8(min(255, int(newB))),
+		A: uint8(a / 256),
+	}
+}
+
+func toGrayscale(img image.Image) image.Image {
+	bounds := img.Bounds()
+	grayImg := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			grayImg.SetRGBA(x, y, grayAt(img, x, y))
+		}
+	}
+	return grayImg
+}
+
+func toSepia(img image.Image) image.Image {
+	bounds := img.Bounds()
+	sepiaImg := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sepiaImg.SetRGBA(x, y, sepiaAt(img, x, y))
+		}
+	}
+	return sepiaImg
+}
+
+// parseFilters turns a comma-separated list like "grayscale,sepia" into the
+// chain of Filters a pipeline stage should apply, in order. A filter name
+// may carry its own parameters after a colon, as semicolon-separated
+// key=value pairs, e.g. "sauvola:k=0.5;w=25,grayscale".
+func parseFilters(spec string) ([]Filter, error) {
+	var filters []Filter
+	for _, token := range strings.Split(spec, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		filter, err := parseFilterToken(token)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("no filters specified")
+	}
+	return filters, nil
+}
+
+// parseFilterToken builds one Filter from a single "-filters" entry, e.g.
+// "grayscale" or "sauvola:k=0.5;w=25".
+func parseFilterToken(token string) (Filter, error) {
+	name, paramStr, hasParams := strings.Cut(token, ":")
+
+	params := map[string]string{}
+	if hasParams {
+		for _, kv := range strings.Split(paramStr, ";") {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid parameter %q for filter %q: expected key=value", kv, name)
+			}
+			params[key] = value
+		}
+	}
+
+	switch name {
+	case "grayscale":
+		return grayscaleFilter{}, nil
+	case "sepia":
+		return sepiaFilter{}, nil
+	case "sauvola":
+		return parseSauvolaParams(params)
+	default:
+		return nil, fmt.Errorf("unknown filter %q", name)
+	}
+}
+
+func parseSauvolaParams(params map[string]string) (Filter, error) {
+	k := defaultSauvolaK
+	if v, ok := params["k"]; ok {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sauvola k %q: %w", v, err)
+		}
+		k = parsed
+	}
+
+	w := defaultSauvolaW
+	if v, ok := params["w"]; ok {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sauvola w %q: %w", v, err)
+		}
+		if parsed < 1 {
+			return nil, fmt.Errorf("invalid sauvola w %q: must be at least 1", v)
+		}
+		if parsed%2 == 0 {
+			return nil, fmt.Errorf("invalid sauvola w %q: must be odd, so the window has a center pixel", v)
+		}
+		w = parsed
+	}
+
+	return newSauvolaFilter(k, w), nil
+}
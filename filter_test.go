@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestParseSauvolaParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{"defaults", map[string]string{}, false},
+		{"valid k and w", map[string]string{"k": "0.5", "w": "25"}, false},
+		{"k not a number", map[string]string{"k": "nope"}, true},
+		{"w not a number", map[string]string{"w": "nope"}, true},
+		{"w zero", map[string]string{"w": "0"}, true},
+		{"w negative", map[string]string{"w": "-5"}, true},
+		{"w even", map[string]string{"w": "10"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseSauvolaParams(tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got filter %+v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseFiltersSauvolaToken(t *testing.T) {
+	filters, err := parseFilters("sauvola:k=0.5;w=25")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filters) != 1 {
+		t.Fatalf("len(filters) = %d, want 1", len(filters))
+	}
+	sf, ok := filters[0].(sauvolaFilter)
+	if !ok {
+		t.Fatalf("filters[0] is %T, want sauvolaFilter", filters[0])
+	}
+	if sf.k != 0.5 || sf.w != 25 {
+		t.Errorf("got {k:%v w:%v}, want {k:0.5 w:25}", sf.k, sf.w)
+	}
+}
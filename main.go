@@ -1,68 +1,31 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
-	"image/color"
-	"image/jpeg"
-	"io/fs"
 	"log"
+	"net/http"
 	"os"
-	"path/filepath"
 	"runtime"
-	"sync"
 	"time"
 )
 
+// ImageTask carries one image through the pipeline, along with where and in
+// what format it should eventually be written.
 type ImageTask struct {
-	FilePath string
-	Img      image.Image
+	FilePath   string
+	Img        image.Image
+	OutputPath string
+	Format     string
+	Quality    int
 }
 
-func toGrayscale(img image.Image) image.Image {
-	bounds := img.Bounds()
-	grayImg := image.NewRGBA(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			originalColor := img.At(x, y)
-			r, g, b, a := originalColor.RGBA()
-
-			gray := uint8((0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 256)
-			grayImg.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: uint8(a / 256)})
-		}
-	}
-	return grayImg
-}
-
-func toSepia(img image.Image) image.Image {
-	bounds := img.Bounds()
-	sepiaImg := image.NewRGBA(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, a := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA).RGBA()
-
-			r8, g8, b8 := uint8(r/257), uint8(g/257), uint8(b/257)
-
-			newR := float64(r8)*0.393 + float64(g8)*0.769 + float64(b8)*0.189
-			newG := float64(r8)*0.349 + float64(g8)*0.686 + float64(b8)*0.168
-			newB := float64(r8)*0.272 + float64(g8)*0.534 + float64(b8)*0.131
-
-			sepiaImg.SetRGBA(x, y, color.RGBA{
-				R: uint8(min(255, int(newR))),
-				G: uint8(min(255, int(newG))),
-				B: uint8(min(255, int(newB))),
-				A: uint8(a / 256),
-			})
-		}
-	}
-	return sepiaImg
-}
+func runSequential(filePaths []string, filters []Filter, o options) {
+	fmt.Println("--- Послідовний Режим ---")
+	start := time.Now()
 
-func loadWorker(filePaths <-chan string, tasksChan chan<- ImageTask, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for path := range filePaths {
+	for _, path := range filePaths {
 		file, err := os.Open(path)
 		if err != nil {
 			log.Printf("Error opening file %s: %v", path, err)
@@ -74,119 +37,75 @@ func loadWorker(filePaths <-chan string, tasksChan chan<- ImageTask, wg *sync.Wa
 			log.Printf("Error decoding image %s: %v", path, err)
 			continue
 		}
-		tasksChan <- ImageTask{FilePath: path, Img: img}
-	}
-}
 
-func processWorker(tasksChan <-chan ImageTask, resultsChan chan<- ImageTask, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for task := range tasksChan {
-		grayImg := toGrayscale(task.Img)
-		sepiaImg := toSepia(grayImg)
-
-		task.Img = sepiaImg
-		resultsChan <- task
-	}
-}
-
-func saveWorker(resultsChan <-chan ImageTask, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for task := range resultsChan {
-		outputPath := filepath.Join("output_parallel", filepath.Base(task.FilePath))
+		for _, f := range filters {
+			img, err = tiler.Apply(f, img)
+			if err != nil {
+				log.Printf("Error applying filter to %s: %v", path, err)
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
 
+		outputPath := outputPathFor(o, path)
+		if !o.Overwrite {
+			os.MkdirAll(o.Output, 0o755)
+		}
 		outFile, err := os.Create(outputPath)
 		if err != nil {
 			log.Printf("Error creating output file %s: %v", outputPath, err)
 			continue
 		}
-		defer outFile.Close()
-
-		if err := jpeg.Encode(outFile, task.Img, nil); err != nil {
-			log.Printf("Error encoding JPEG %s: %v", outputPath, err)
+		err = encodeImage(outFile, img, formatFor(o, path), o.Quality)
+		outFile.Close()
+		if err != nil {
+			log.Printf("Error encoding %s: %v", outputPath, err)
 		}
 	}
-}
-
-func runParallelPipeline(filePaths []string, numWorkers int) {
-	fmt.Printf("--- Паралельний Режим (Workers: %d) ---\n", numWorkers)
-	start := time.Now()
-
-	filesChan := make(chan string, len(filePaths))
-	tasksChan := make(chan ImageTask, numWorkers)
-	resultsChan := make(chan ImageTask, numWorkers)
-
-	var wgLoad sync.WaitGroup
-	var wgProcess sync.WaitGroup
-	var wgSave sync.WaitGroup
-
-	os.MkdirAll("output_parallel", fs.ModePerm)
-
-	wgSave.Add(1)
-	go func() {
-		saveWorker(resultsChan, &wgSave)
-	}()
-
-	for i := 0; i < numWorkers; i++ {
-		wgProcess.Add(1)
-		go processWorker(tasksChan, resultsChan, &wgProcess)
-	}
-
-	wgLoad.Add(1)
-	go func() {
-		loadWorker(filesChan, tasksChan, &wgLoad)
-	}()
-
-	for _, path := range filePaths {
-		filesChan <- path
-	}
-	close(filesChan)
-
-	wgLoad.Wait()
-	close(tasksChan)
-
-	wgProcess.Wait()
-	close(resultsChan)
-
-	wgSave.Wait()
 
 	duration := time.Since(start)
-	fmt.Printf("Час виконання (Паралельний): %s\n", duration)
+	fmt.Printf("Час виконання (Послідовний): %s\n", duration)
 }
 
-func runSequential(filePaths []string) {
-	fmt.Println("--- Послідовний Режим ---")
-	start := time.Now()
-
-	os.MkdirAll("output_sequential", fs.ModePerm)
+func newPipelineFor(filters []Filter, o options) *Pipeline {
+	pipeline := NewPipeline(
+		filters,
+		StageConfig{Workers: 1, BufferSize: o.Workers},
+		StageConfig{Workers: o.Workers, BufferSize: o.Workers},
+		StageConfig{Workers: 1, BufferSize: o.Workers},
+		o.Workers*4,
+		o,
+	)
+	pipeline.Progress = newProgress(o)
+	return pipeline
+}
 
-	for _, path := range filePaths {
-		file, err := os.Open(path)
-		if err != nil {
-			log.Printf("Error opening file %s: %v", path, err)
-			continue
-		}
-		img, _, err := image.Decode(file)
-		file.Close()
-		if err != nil {
-			log.Printf("Error decoding image %s: %v", path, err)
-			continue
-		}
+func runDaemon(filters []Filter, o options) {
+	cache, err := openBoltCache(o.CachePath)
+	if err != nil {
+		log.Fatalf("daemon: %v", err)
+	}
+	defer cache.Close()
 
-		grayImg := toGrayscale(img)
-		sepiaImg := toSepia(grayImg)
+	scheduler := NewScheduler(
+		newDirListProcessor(o.Input, o.Recursive),
+		cache,
+		newPipelineFor(filters, o),
+		o.ScanInterval,
+		o.Input,
+	)
 
-		outputPath := filepath.Join("output_sequential", filepath.Base(path))
-		outFile, err := os.Create(outputPath)
-		if err != nil {
-			log.Printf("Error creating output file %s: %v", outputPath, err)
-			continue
+	go func() {
+		log.Printf("daemon: listening on %s (/healthz, /stats, /reprocess)", o.HTTPAddr)
+		if err := http.ListenAndServe(o.HTTPAddr, newDaemonMux(scheduler)); err != nil {
+			log.Fatalf("daemon: http server: %v", err)
 		}
-		jpeg.Encode(outFile, sepiaImg, nil)
-		outFile.Close()
-	}
+	}()
 
-	duration := time.Since(start)
-	fmt.Printf("Час виконання (Послідовний): %s\n", duration)
+	log.Printf("daemon: watching %s every %s", o.Input, o.ScanInterval)
+	scheduler.Run(context.Background())
 }
 
 func main() {
@@ -194,28 +113,43 @@ func main() {
 
 	numCPU := runtime.NumCPU()
 	runtime.GOMAXPROCS(numCPU)
+
+	o, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	fmt.Printf("Використовується ядер CPU: %d\n", numCPU)
 
-	testDir := "input_images"
-	os.MkdirAll(testDir, fs.ModePerm)
+	filters, err := parseFilters(o.Filters)
+	if err != nil {
+		log.Fatalf("invalid -filters: %v", err)
+	}
 
-	filePaths, err := filepath.Glob(filepath.Join(testDir, "*.jpg"))
+	if o.Daemon {
+		runDaemon(filters, o)
+		return
+	}
+
+	filePaths, err := collectInputFiles(o)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	if len(filePaths) == 0 {
-		fmt.Println("У директорії 'input_images' відсутні зображення.")
+		fmt.Println("Не знайдено зображень за вказаним шляхом.")
+		return
 	}
 
 	fmt.Printf("Знайдено файлів для обробки: %d\n", len(filePaths))
 	fmt.Println("--------------------------------------------------")
 
-	runSequential(filePaths)
-
-	fmt.Println("--------------------------------------------------")
-
-	runParallelPipeline(filePaths, numCPU)
+	switch o.Mode {
+	case "sequential":
+		runSequential(filePaths, filters, o)
+	case "parallel":
+		newPipelineFor(filters, o).Run(filePaths)
+	}
 
 	fmt.Println("--------------------------------------------------")
 	fmt.Println("Обробку успішно завершено.")
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// StageConfig controls how many goroutines a pipeline stage runs and how
+// deep its input channel's buffer is. Stages sit between each other on
+// channels, so a slow stage (usually I/O) can be given more buffer without
+// forcing every other stage to match it.
+type StageConfig struct {
+	Workers    int
+	BufferSize int
+}
+
+// stageMetrics tracks how busy a stage has been, so a user comparing two
+// stages can see which one is the bottleneck.
+type stageMetrics struct {
+	name      string
+	processed int64
+	busyNs    int64
+}
+
+func (m *stageMetrics) record(d time.Duration) {
+	atomic.AddInt64(&m.busyNs, int64(d))
+	atomic.AddInt64(&m.processed, 1)
+}
+
+func (m *stageMetrics) line(elapsed time.Duration, queueDepth int) string {
+	processed := atomic.LoadInt64(&m.processed)
+	busy := time.Duration(atomic.LoadInt64(&m.busyNs))
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(processed) / elapsed.Seconds()
+	}
+	return fmt.Sprintf("%-12s processed=%-6d busy=%-12s throughput=%6.1f img/s queue=%d",
+		m.name, processed, busy.Round(time.Millisecond), throughput, queueDepth)
+}
+
+// Pipeline wires a load stage, one stage per Filter, and a save stage
+// together with their own channels so each can be sized independently.
+// Decoding is further bounded by DecodeConcurrency, independently of
+// LoadCfg.Workers, since decoding is I/O bound and can be over-provisioned
+// relative to the CPU-bound filter stages.
+type Pipeline struct {
+	Filters           []Filter
+	LoadCfg           StageConfig
+	FilterCfg         StageConfig
+	SaveCfg           StageConfig
+	DecodeConcurrency int
+	Options           options
+	Progress          Progress
+
+	stageChans []chan ImageTask
+	metrics    []*stageMetrics
+}
+
+func NewPipeline(filters []Filter, loadCfg, filterCfg, saveCfg StageConfig, decodeConcurrency int, opts options) *Pipeline {
+	return &Pipeline{
+		Filters:           filters,
+		LoadCfg:           loadCfg,
+		FilterCfg:         filterCfg,
+		SaveCfg:           saveCfg,
+		DecodeConcurrency: decodeConcurrency,
+		Options:           opts,
+	}
+}
+
+// Run processes filePaths to completion, blocking until every stage has
+// drained, then prints a per-stage metrics report.
+func (p *Pipeline) Run(filePaths []string) {
+	start := time.Now()
+
+	if p.Progress == nil {
+		p.Progress = noProgress{}
+	}
+	tracker := NewProgressTracker(len(filePaths))
+
+	if !p.Options.Overwrite {
+		os.MkdirAll(p.Options.Output, 0o755)
+	}
+
+	filesChan := make(chan string, p.LoadCfg.BufferSize)
+	p.stageChans = make([]chan ImageTask, len(p.Filters)+1)
+	for i := range p.stageChans {
+		p.stageChans[i] = make(chan ImageTask, p.FilterCfg.BufferSize)
+	}
+	// The last channel feeds the save stage, so it takes the save stage's buffer.
+	p.stageChans[len(p.stageChans)-1] = make(chan ImageTask, p.SaveCfg.BufferSize)
+	p.metrics = make([]*stageMetrics, 0, len(p.Filters)+2)
+
+	decodeSem := semaphore.NewWeighted(int64(p.DecodeConcurrency))
+
+	loadMetrics := &stageMetrics{name: "load"}
+	p.metrics = append(p.metrics, loadMetrics)
+	var wgLoad sync.WaitGroup
+	for i := 0; i < p.LoadCfg.Workers; i++ {
+		wgLoad.Add(1)
+		go runLoadStage(filesChan, p.stageChans[0], &wgLoad, loadMetrics, decodeSem, p.Options, tracker)
+	}
+
+	filterWGs := make([]*sync.WaitGroup, len(p.Filters))
+	for i, filter := range p.Filters {
+		m := &stageMetrics{name: fmt.Sprintf("filter:%d", i)}
+		p.metrics = append(p.metrics, m)
+
+		wg := &sync.WaitGroup{}
+		filterWGs[i] = wg
+		in, out := p.stageChans[i], p.stageChans[i+1]
+		for w := 0; w < p.FilterCfg.Workers; w++ {
+			wg.Add(1)
+			go runFilterStage(filter, in, out, wg, m)
+		}
+	}
+
+	saveMetrics := &stageMetrics{name: "save"}
+	p.metrics = append(p.metrics, saveMetrics)
+	var wgSave sync.WaitGroup
+	last := p.stageChans[len(p.stageChans)-1]
+	for i := 0; i < p.SaveCfg.Workers; i++ {
+		wgSave.Add(1)
+		go runSaveStage(last, &wgSave, saveMetrics, tracker)
+	}
+
+	stopTicker := make(chan struct{})
+	tickerDone := make(chan struct{})
+	go func() {
+		defer close(tickerDone)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.Progress.Report(tracker.Snapshot(p.stageDepths()))
+			case <-stopTicker:
+				return
+			}
+		}
+	}()
+
+	for _, path := range filePaths {
+		filesChan <- path
+	}
+	close(filesChan)
+
+	wgLoad.Wait()
+	close(p.stageChans[0])
+
+	for i, wg := range filterWGs {
+		wg.Wait()
+		close(p.stageChans[i+1])
+	}
+
+	wgSave.Wait()
+
+	close(stopTicker)
+	<-tickerDone
+	p.Progress.Done(tracker.Snapshot(p.stageDepths()))
+
+	p.report(time.Since(start))
+}
+
+// stageDepths reports how many tasks are currently queued on each stage's
+// output channel (waiting for the next stage), keyed by the same names used
+// in p.metrics. The save stage has no output channel and is not included.
+func (p *Pipeline) stageDepths() map[string]int64 {
+	depths := make(map[string]int64, len(p.metrics))
+	for i, m := range p.metrics {
+		if i < len(p.stageChans) {
+			depths[m.name] = int64(len(p.stageChans[i]))
+		}
+	}
+	return depths
+}
+
+func (p *Pipeline) report(elapsed time.Duration) {
+	fmt.Printf("--- Pipeline stats (%s) ---\n", elapsed.Round(time.Millisecond))
+	for i, m := range p.metrics {
+		depth := 0
+		if i < len(p.stageChans) {
+			depth = len(p.stageChans[i])
+		}
+		fmt.Println(m.line(elapsed, depth))
+	}
+}
+
+func runLoadStage(filePaths <-chan string, out chan<- ImageTask, wg *sync.WaitGroup, m *stageMetrics, decodeSem *semaphore.Weighted, o options, tracker *ProgressTracker) {
+	defer wg.Done()
+	ctx := context.Background()
+	for path := range filePaths {
+		started := time.Now()
+
+		if err := decodeSem.Acquire(ctx, 1); err != nil {
+			log.Printf("load: acquiring decode slot for %s: %v", path, err)
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			decodeSem.Release(1)
+			log.Printf("load: opening %s: %v", path, err)
+			continue
+		}
+		if info, err := file.Stat(); err == nil {
+			tracker.AddBytesRead(info.Size())
+		}
+		img, _, err := image.Decode(file)
+		file.Close()
+		decodeSem.Release(1)
+		if err != nil {
+			log.Printf("load: decoding %s: %v", path, err)
+			continue
+		}
+		m.record(time.Since(started))
+		out <- ImageTask{
+			FilePath:   path,
+			Img:        img,
+			OutputPath: outputPathFor(o, path),
+			Format:     formatFor(o, path),
+			Quality:    o.Quality,
+		}
+	}
+}
+
+// tiler splits each image into tiles so a filter with a RectApplier fast
+// path can spread a single large image across multiple goroutines, instead
+// of one filter stage worker sitting on it alone.
+var tiler = NewTiler(defaultTileSize, defaultTileSize)
+
+func runFilterStage(filter Filter, in <-chan ImageTask, out chan<- ImageTask, wg *sync.WaitGroup, m *stageMetrics) {
+	defer wg.Done()
+	for task := range in {
+		started := time.Now()
+		result, err := tiler.Apply(filter, task.Img)
+		if err != nil {
+			log.Printf("filter: %s: %v", task.FilePath, err)
+			continue
+		}
+		task.Img = result
+		m.record(time.Since(started))
+		out <- task
+	}
+}
+
+func runSaveStage(in <-chan ImageTask, wg *sync.WaitGroup, m *stageMetrics, tracker *ProgressTracker) {
+	defer wg.Done()
+	for task := range in {
+		started := time.Now()
+
+		if err := os.MkdirAll(filepath.Dir(task.OutputPath), 0o755); err != nil {
+			log.Printf("save: creating output dir for %s: %v", task.OutputPath, err)
+			continue
+		}
+		outFile, err := os.Create(task.OutputPath)
+		if err != nil {
+			log.Printf("save: creating %s: %v", task.OutputPath, err)
+			continue
+		}
+		counting := &countingWriter{w: outFile}
+		err = encodeImage(counting, task.Img, task.Format, task.Quality)
+		outFile.Close()
+		if err != nil {
+			log.Printf("save: encoding %s: %v", task.OutputPath, err)
+			continue
+		}
+		tracker.AddBytesWritten(counting.n)
+		tracker.AddFileDone()
+		m.record(time.Since(started))
+	}
+}
+
+// countingWriter tallies bytes written through it, so the save stage can
+// report progress without stat-ing the output file back off disk.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// progressStderr is where jsonProgress writes; a var so tests could swap it.
+var progressStderr = os.Stderr
+
+// ProgressSnapshot is a point-in-time summary of a pipeline run, handed to a
+// Progress each time it's reported.
+type ProgressSnapshot struct {
+	FilesDone    int64
+	FilesTotal   int64
+	BytesRead    int64
+	BytesWritten int64
+	Elapsed      time.Duration
+	StageDepths  map[string]int64
+}
+
+// ThroughputMBps is the combined read+write throughput so far, in MB/s.
+func (s ProgressSnapshot) ThroughputMBps() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	totalBytes := float64(s.BytesRead + s.BytesWritten)
+	return totalBytes / 1e6 / s.Elapsed.Seconds()
+}
+
+// ETA estimates the remaining time from the average time per file so far.
+func (s ProgressSnapshot) ETA() time.Duration {
+	if s.FilesDone == 0 || s.FilesDone >= s.FilesTotal {
+		return 0
+	}
+	perFile := s.Elapsed / time.Duration(s.FilesDone)
+	return perFile * time.Duration(s.FilesTotal-s.FilesDone)
+}
+
+// Progress is how a Pipeline reports its own execution, so callers can plug
+// in a terminal renderer, a JSON stream for CI/log scraping, or nothing.
+type Progress interface {
+	Report(s ProgressSnapshot)
+	Done(s ProgressSnapshot)
+}
+
+// noProgress discards every report; Run falls back to it when the Pipeline's
+// Progress field is nil, so Run works without one being configured.
+type noProgress struct{}
+
+func (noProgress) Report(ProgressSnapshot) {}
+func (noProgress) Done(ProgressSnapshot)   {}
+
+// ProgressTracker accumulates the counters a Pipeline updates as tasks move
+// through it. It's safe for concurrent use by every stage worker.
+type ProgressTracker struct {
+	filesTotal   int64
+	filesDone    int64
+	bytesRead    int64
+	bytesWritten int64
+	start        time.Time
+}
+
+func NewProgressTracker(filesTotal int) *ProgressTracker {
+	return &ProgressTracker{filesTotal: int64(filesTotal), start: time.Now()}
+}
+
+func (t *ProgressTracker) AddFileDone()            { atomic.AddInt64(&t.filesDone, 1) }
+func (t *ProgressTracker) AddBytesRead(n int64)    { atomic.AddInt64(&t.bytesRead, n) }
+func (t *ProgressTracker) AddBytesWritten(n int64) { atomic.AddInt64(&t.bytesWritten, n) }
+
+// Snapshot reads the tracker's counters along with the current depth of
+// each named stage channel.
+func (t *ProgressTracker) Snapshot(stageDepths map[string]int64) ProgressSnapshot {
+	return ProgressSnapshot{
+		FilesDone:    atomic.LoadInt64(&t.filesDone),
+		FilesTotal:   atomic.LoadInt64(&t.filesTotal),
+		BytesRead:    atomic.LoadInt64(&t.bytesRead),
+		BytesWritten: atomic.LoadInt64(&t.bytesWritten),
+		Elapsed:      time.Since(t.start),
+		StageDepths:  stageDepths,
+	}
+}
+
+// byteSize formats n bytes human-readably (KiB/MiB/GiB), the same pattern as
+// bytefmt.ByteSize.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// jsonProgress emits one JSON object per report on stderr, for CI/log
+// scraping rather than an interactive terminal.
+type jsonProgress struct{}
+
+func newJSONProgress() jsonProgress { return jsonProgress{} }
+
+func (jsonProgress) Report(s ProgressSnapshot) { printProgressJSON(s, false) }
+func (jsonProgress) Done(s ProgressSnapshot)   { printProgressJSON(s, true) }
+
+func printProgressJSON(s ProgressSnapshot, done bool) {
+	fmt.Fprintf(progressStderr, `{"done":%t,"files_done":%d,"files_total":%d,"bytes_read":%d,"bytes_written":%d,"throughput_mbps":%.2f,"eta_seconds":%.1f}`+"\n",
+		done, s.FilesDone, s.FilesTotal, s.BytesRead, s.BytesWritten, s.ThroughputMBps(), s.ETA().Seconds())
+}
@@ -0,0 +1,37 @@
+//go:build fancyprogress
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// terminalProgress renders a block-character progress bar instead of the
+// plain status line, for users who build with -tags fancyprogress.
+type terminalProgress struct{}
+
+func newTerminalProgress() Progress { return terminalProgress{} }
+
+const barWidth = 30
+
+func (terminalProgress) Report(s ProgressSnapshot) {
+	fmt.Fprintf(progressStderr, "\r%s", progressBar(s))
+}
+
+func (terminalProgress) Done(s ProgressSnapshot) {
+	fmt.Fprintf(progressStderr, "\r%s\n", progressBar(s))
+}
+
+func progressBar(s ProgressSnapshot) string {
+	frac := 0.0
+	if s.FilesTotal > 0 {
+		frac = float64(s.FilesDone) / float64(s.FilesTotal)
+	}
+	filled := int(frac * barWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+
+	return fmt.Sprintf("[%s] %d/%d | %s read, %s written | %.1f MB/s | ETA %s",
+		bar, s.FilesDone, s.FilesTotal, byteSize(s.BytesRead), byteSize(s.BytesWritten),
+		s.ThroughputMBps(), s.ETA().Round(1e9))
+}
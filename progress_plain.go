@@ -0,0 +1,26 @@
+//go:build !fancyprogress
+
+package main
+
+import "fmt"
+
+// terminalProgress re-draws a single status line with \r, so it needs no
+// external dependency. Build with -tags fancyprogress for a bar-style
+// renderer instead.
+type terminalProgress struct{}
+
+func newTerminalProgress() Progress { return terminalProgress{} }
+
+func (terminalProgress) Report(s ProgressSnapshot) {
+	fmt.Fprintf(progressStderr, "\r%s", progressLine(s))
+}
+
+func (terminalProgress) Done(s ProgressSnapshot) {
+	fmt.Fprintf(progressStderr, "\r%s\n", progressLine(s))
+}
+
+func progressLine(s ProgressSnapshot) string {
+	return fmt.Sprintf("%d/%d files | read %s | written %s | %.1f MB/s | ETA %s",
+		s.FilesDone, s.FilesTotal, byteSize(s.BytesRead), byteSize(s.BytesWritten),
+		s.ThroughputMBps(), s.ETA().Round(1e9))
+}
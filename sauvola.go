@@ -0,0 +1,195 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// sauvolaFilter performs Sauvola local-threshold binarization, useful for
+// cleaning up scanned documents. It computes a per-pixel threshold from the
+// local mean and standard deviation over a w×w window, via two integral
+// images (summed-area tables) so each window's sum and sum-of-squares can be
+// read in O(1) regardless of window size.
+type sauvolaFilter struct {
+	k float64
+	w int
+}
+
+func newSauvolaFilter(k float64, w int) sauvolaFilter {
+	return sauvolaFilter{k: k, w: w}
+}
+
+// sauvolaR is the dynamic range of the standard deviation, fixed at 128 for
+// 8-bit grayscale images as in Sauvola & Pietikäinen's original paper.
+const sauvolaR = 128.0
+
+// Defaults for the "sauvola" filter name, matching the values in the
+// original paper.
+const (
+	defaultSauvolaK = 0.34
+	defaultSauvolaW = 19
+)
+
+func (f sauvolaFilter) Apply(img image.Image) (image.Image, error) {
+	gray := toGrayscale(img).(*image.RGBA)
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	sum := buildIntegralImage(gray, func(v uint64) uint64 { return v })
+	sumSq := buildIntegralImage(gray, func(v uint64) uint64 { return v * v })
+
+	out := image.NewRGBA(bounds)
+
+	half := f.w / 2
+	numWorkers := runtime.NumCPU()
+	rows := make(chan int, height)
+	var wg sync.WaitGroup
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := range rows {
+				for x := 0; x < width; x++ {
+					x0 := max(0, x-half)
+					x1 := min(width-1, x+half)
+					y0 := max(0, y-half)
+					y1 := min(height-1, y+half)
+
+					area := uint64((x1 - x0 + 1) * (y1 - y0 + 1))
+					s := sum.rectSum(x0, y0, x1, y1)
+					sq := sumSq.rectSum(x0, y0, x1, y1)
+
+					mean := float64(s) / float64(area)
+					variance := float64(sq)/float64(area) - mean*mean
+					if variance < 0 {
+						variance = 0
+					}
+					stddev := math.Sqrt(variance)
+
+					threshold := mean * (1 + f.k*(stddev/sauvolaR-1))
+
+					px := gray.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y).R
+					value := uint8(0)
+					if float64(px) >= threshold {
+						value = 255
+					}
+					out.SetRGBA(bounds.Min.X+x, bounds.Min.Y+y, color.RGBA{R: value, G: value, B: value, A: 255})
+				}
+			}
+		}()
+	}
+
+	for y := 0; y < height; y++ {
+		rows <- y
+	}
+	close(rows)
+	wg.Wait()
+
+	return out, nil
+}
+
+// Halo reports the neighbourhood, in pixels, sauvolaFilter's tile fast-path
+// needs to read around a tile in order to compute each pixel's w×w window.
+func (f sauvolaFilter) Halo() int {
+	return f.w / 2
+}
+
+// ApplyRect is sauvolaFilter's tile fast-path: it builds an integral image
+// over just r padded by Halo() pixels (clipped to src's bounds) rather than
+// the whole image, so tiles can be thresholded independently.
+func (f sauvolaFilter) ApplyRect(dst *image.RGBA, src image.Image, r image.Rectangle) {
+	halo := f.Halo()
+	padded := r.Inset(-halo).Intersect(src.Bounds())
+
+	gray := grayscalePatch(src, padded)
+	sum := buildIntegralImage(gray, func(v uint64) uint64 { return v })
+	sumSq := buildIntegralImage(gray, func(v uint64) uint64 { return v * v })
+
+	width, height := padded.Dx(), padded.Dy()
+	half := f.w / 2
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ly := y - padded.Min.Y
+		for x := r.Min.X; x < r.Max.X; x++ {
+			lx := x - padded.Min.X
+
+			x0 := max(0, lx-half)
+			x1 := min(width-1, lx+half)
+			y0 := max(0, ly-half)
+			y1 := min(height-1, ly+half)
+
+			area := uint64((x1 - x0 + 1) * (y1 - y0 + 1))
+			s := sum.rectSum(x0, y0, x1, y1)
+			sq := sumSq.rectSum(x0, y0, x1, y1)
+
+			mean := float64(s) / float64(area)
+			variance := float64(sq)/float64(area) - mean*mean
+			if variance < 0 {
+				variance = 0
+			}
+			stddev := math.Sqrt(variance)
+
+			threshold := mean * (1 + f.k*(stddev/sauvolaR-1))
+
+			px := gray.RGBAAt(lx, ly).R
+			value := uint8(0)
+			if float64(px) >= threshold {
+				value = 255
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: value, G: value, B: value, A: 255})
+		}
+	}
+}
+
+// grayscalePatch converts just the rect region of src to grayscale, as an
+// image.RGBA whose own bounds start at (0,0) regardless of rect's position
+// in src, so tile-local integral images don't need to carry src's offset.
+func grayscalePatch(src image.Image, rect image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			out.SetRGBA(x-rect.Min.X, y-rect.Min.Y, grayAt(src, x, y))
+		}
+	}
+	return out
+}
+
+// integralImage is a summed-area table over a grayscale image: the sum of
+// any rectangular window can be read in O(1) via rectSum. Row 0 and column 0
+// are an implicit zero border, so rectSum needs no special-casing at the
+// image edges.
+type integralImage struct {
+	data   []uint64
+	stride int
+}
+
+func buildIntegralImage(gray *image.RGBA, transform func(uint64) uint64) integralImage {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	stride := width + 1
+
+	data := make([]uint64, stride*(height+1))
+	for y := 0; y < height; y++ {
+		var rowSum uint64
+		for x := 0; x < width; x++ {
+			v := transform(uint64(gray.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y).R))
+			rowSum += v
+
+			idx := (y+1)*stride + (x + 1)
+			above := y*stride + (x + 1)
+			data[idx] = data[above] + rowSum
+		}
+	}
+	return integralImage{data: data, stride: stride}
+}
+
+// rectSum returns the sum over the inclusive pixel rectangle
+// [x0,x1]x[y0,y1] via S(x2,y2) - S(x1,y2) - S(x2,y1) + S(x1,y1).
+func (t integralImage) rectSum(x0, y0, x1, y1 int) uint64 {
+	at := func(x, y int) uint64 { return t.data[y*t.stride+x] }
+	return at(x1+1, y1+1) - at(x0, y1+1) - at(x1+1, y0) + at(x0, y0)
+}
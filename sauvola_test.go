@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// gradientImage builds a w x h RGBA image whose pixel value ramps across x
+// and y, so Sauvola's local threshold actually varies across the image
+// instead of being trivially uniform.
+func gradientImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*17 + y*31) % 256)
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+// TestSauvolaTiledMatchesWhole checks that running sauvolaFilter through a
+// Tiler (its ApplyRect fast path, exercised across several small tiles and
+// therefore several halo-padded boundaries) produces the same thresholded
+// output as calling Apply directly on the whole image.
+func TestSauvolaTiledMatchesWhole(t *testing.T) {
+	img := gradientImage(37, 23) // deliberately not a multiple of the tile size
+	filter := newSauvolaFilter(defaultSauvolaK, 9)
+
+	whole, err := filter.Apply(img)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	tiler := NewTiler(5, 7) // small, uneven tiles so halos cross tile edges
+	tiled, err := tiler.Apply(filter, img)
+	if err != nil {
+		t.Fatalf("tiler.Apply: %v", err)
+	}
+
+	bounds := img.Bounds()
+	wholeRGBA := whole.(*image.RGBA)
+	tiledRGBA := tiled.(*image.RGBA)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			want := wholeRGBA.RGBAAt(x, y)
+			got := tiledRGBA.RGBAAt(x, y)
+			if want != got {
+				t.Fatalf("pixel (%d,%d): tiled=%v whole=%v", x, y, got, want)
+			}
+		}
+	}
+}
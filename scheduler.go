@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessedRecord is what a Cache remembers about a file that has already
+// gone through the pipeline, so a later scan can tell whether it changed.
+type ProcessedRecord struct {
+	Path        string
+	Hash        string
+	ModTime     time.Time
+	ProcessedAt time.Time
+}
+
+// Cache is the on-disk store of already-processed files, keyed by path, so
+// a Scheduler can skip work it already did across restarts.
+type Cache interface {
+	Get(path string) (ProcessedRecord, bool, error)
+	Put(record ProcessedRecord) error
+	Close() error
+}
+
+// ListProcessor discovers the files that make up one unit of work for a
+// Scheduler tick. The default is a directory scan, but this is pluggable so
+// something other than a filesystem (e.g. a queue) could stand in for it.
+type ListProcessor interface {
+	List(ctx context.Context) ([]string, error)
+}
+
+// dirListProcessor lists supported image files under a root directory,
+// honoring the same recursion rule as the one-shot CLI's -r flag.
+type dirListProcessor struct {
+	root      string
+	recursive bool
+}
+
+func newDirListProcessor(root string, recursive bool) dirListProcessor {
+	return dirListProcessor{root: root, recursive: recursive}
+}
+
+func (d dirListProcessor) List(ctx context.Context) ([]string, error) {
+	return collectInputFiles(options{Input: d.root, Recursive: d.recursive})
+}
+
+// SchedulerStats is a snapshot of a Scheduler's own progress, served over
+// /stats for the daemon to be poked from outside.
+type SchedulerStats struct {
+	QueueDepth  int              `json:"queue_depth"`
+	LastScanAt  time.Time        `json:"last_scan_at"`
+	StageDepths map[string]int64 `json:"stage_depths"`
+}
+
+// Scheduler periodically asks a ListProcessor for work, skips anything
+// already in its Cache under an unchanged mtime and content hash, and
+// enqueues the rest onto a Pipeline. This is what turns the pipeline from a
+// one-shot batch tool into a long-running background service.
+type Scheduler struct {
+	Lister   ListProcessor
+	Cache    Cache
+	Pipeline *Pipeline
+	Interval time.Duration
+
+	// Root confines Reprocess to files under this directory (normally -i's
+	// value), so a path reaching it over HTTP can't escape onto the rest of
+	// the filesystem.
+	Root string
+
+	mu         sync.Mutex
+	queueDepth int
+	lastScan   time.Time
+
+	// runMu serializes calls into Pipeline.Run: a scheduled tick and an
+	// HTTP-triggered Reprocess can otherwise fire concurrently, and Run
+	// reassigns the pipeline's internal channels and metrics on every call,
+	// which Stats also reads via stageDepths.
+	runMu sync.Mutex
+}
+
+func NewScheduler(lister ListProcessor, cache Cache, pipeline *Pipeline, interval time.Duration, root string) *Scheduler {
+	return &Scheduler{Lister: lister, Cache: cache, Pipeline: pipeline, Interval: interval, Root: root}
+}
+
+// Run blocks, ticking every Interval until ctx is canceled. It scans once
+// immediately so a freshly started daemon doesn't wait a full interval
+// before doing its first pass.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.tick()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick()
+		}
+	}
+}
+
+func (s *Scheduler) tick() {
+	files, err := s.Lister.List(context.Background())
+	if err != nil {
+		log.Printf("scheduler: listing: %v", err)
+		return
+	}
+
+	var fresh []string
+	for _, path := range files {
+		if s.shouldProcess(path) {
+			fresh = append(fresh, path)
+		}
+	}
+
+	s.mu.Lock()
+	s.queueDepth = len(fresh)
+	s.lastScan = time.Now()
+	s.mu.Unlock()
+
+	if len(fresh) == 0 {
+		return
+	}
+
+	log.Printf("scheduler: enqueuing %d new/changed file(s)", len(fresh))
+	s.runPipeline(fresh)
+	for _, path := range fresh {
+		if err := s.markProcessed(path); err != nil {
+			log.Printf("scheduler: recording %s as processed: %v", path, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.queueDepth = 0
+	s.mu.Unlock()
+}
+
+// shouldProcess reports whether path is new or has changed since it was
+// last recorded in the cache. An unchanged mtime is trusted without
+// re-hashing the file; only a changed mtime pays for a fresh hash, so a
+// directory of untouched files stays cheap to rescan.
+func (s *Scheduler) shouldProcess(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	record, ok, err := s.Cache.Get(path)
+	if err != nil || !ok {
+		return true
+	}
+	if info.ModTime().Equal(record.ModTime) {
+		return false
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return true
+	}
+	return hash != record.Hash
+}
+
+func (s *Scheduler) markProcessed(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	return s.Cache.Put(ProcessedRecord{
+		Path:        path,
+		Hash:        hash,
+		ModTime:     info.ModTime(),
+		ProcessedAt: time.Now(),
+	})
+}
+
+// Reprocess forces path back through the pipeline and refreshes its cache
+// entry, regardless of whether it looks unchanged. path must resolve inside
+// s.Root, so a caller (notably the /reprocess HTTP endpoint) can't use it to
+// read arbitrary files off the host.
+func (s *Scheduler) Reprocess(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	if err := s.confine(path); err != nil {
+		return err
+	}
+	s.runPipeline([]string{path})
+	return s.markProcessed(path)
+}
+
+// confine rejects any path that doesn't resolve to somewhere inside s.Root,
+// following symlinks on both sides first so a symlink planted under Root
+// can't point the pipeline at a file outside it.
+func (s *Scheduler) confine(path string) error {
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q is outside %q", errOutsideRoot, path, s.Root)
+	}
+	return nil
+}
+
+// errOutsideRoot is returned by Reprocess when asked for a path outside
+// Root, so callers like the /reprocess HTTP handler can tell it apart from
+// an ordinary stat/pipeline failure and respond with 400 instead of 500.
+var errOutsideRoot = errors.New("path outside scheduler root")
+
+// runPipeline serializes every call into s.Pipeline.Run: a scheduled tick
+// and an HTTP-triggered Reprocess must never run the pipeline at the same
+// time, since Run reassigns the pipeline's channels and metrics from
+// scratch on each call.
+func (s *Scheduler) runPipeline(paths []string) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	s.Pipeline.Run(paths)
+}
+
+func (s *Scheduler) Stats() SchedulerStats {
+	s.runMu.Lock()
+	stageDepths := s.Pipeline.stageDepths()
+	s.runMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		QueueDepth:  s.queueDepth,
+		LastScanAt:  s.lastScan,
+		StageDepths: stageDepths,
+	}
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
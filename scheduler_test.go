@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchedulerConfine(t *testing.T) {
+	root := t.TempDir()
+
+	inside := filepath.Join(root, "a.png")
+	if err := os.WriteFile(inside, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outsideDir := t.TempDir()
+	outside := filepath.Join(outsideDir, "secret.png")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	escape := filepath.Join(root, "escape")
+	if err := os.Symlink(outsideDir, escape); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Scheduler{Root: root}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"inside root", inside, false},
+		{"relative .. escape", filepath.Join(root, "..", filepath.Base(outsideDir), "secret.png"), true},
+		{"absolute outside root", outside, true},
+		{"symlink escape", filepath.Join(escape, "secret.png"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.confine(tt.path)
+			if tt.wantErr && err == nil {
+				t.Fatalf("confine(%q): expected an error, got nil", tt.path)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("confine(%q): unexpected error: %v", tt.path, err)
+			}
+			if tt.wantErr && err != nil && !errors.Is(err, errOutsideRoot) {
+				t.Fatalf("confine(%q): error %v does not wrap errOutsideRoot", tt.path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+const defaultTileSize = 256
+
+// RectApplier is the fast per-tile path a Filter can optionally implement so
+// a Tiler can split one image across multiple goroutines instead of running
+// Apply on a single goroutine for the whole image. Filters that only look at
+// the pixel being written (grayscale, sepia, threshold) can implement it
+// directly; ApplyRect must only write inside r.
+type RectApplier interface {
+	ApplyRect(dst *image.RGBA, src image.Image, r image.Rectangle)
+}
+
+// Tiler splits a large image's bounds into a grid of TileWidth x TileHeight
+// rectangles and runs each one through a Filter's RectApplier fast path on a
+// worker pool bounded by runtime.NumCPU(), so a single huge input doesn't
+// sit on one goroutine while the rest of the pipeline's file-level
+// parallelism leaves N-1 cores idle.
+type Tiler struct {
+	TileWidth  int
+	TileHeight int
+}
+
+func NewTiler(tileWidth, tileHeight int) *Tiler {
+	if tileWidth <= 0 {
+		tileWidth = defaultTileSize
+	}
+	if tileHeight <= 0 {
+		tileHeight = defaultTileSize
+	}
+	return &Tiler{TileWidth: tileWidth, TileHeight: tileHeight}
+}
+
+// tiles returns the grid of non-overlapping rectangles covering bounds.
+func (t *Tiler) tiles(bounds image.Rectangle) []image.Rectangle {
+	var rects []image.Rectangle
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += t.TileHeight {
+		for x := bounds.Min.X; x < bounds.Max.X; x += t.TileWidth {
+			rects = append(rects, image.Rect(
+				x, y,
+				min(x+t.TileWidth, bounds.Max.X),
+				min(y+t.TileHeight, bounds.Max.Y),
+			))
+		}
+	}
+	return rects
+}
+
+// Apply runs filter over src, split into tiles across a worker pool if the
+// filter implements RectApplier, or falls back to a single call to Apply
+// for filters that only know how to process a whole image.
+func (t *Tiler) Apply(filter Filter, src image.Image) (image.Image, error) {
+	applier, ok := filter.(RectApplier)
+	if !ok {
+		return filter.Apply(src)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	tiles := t.tiles(bounds)
+	work := make(chan image.Rectangle, len(tiles))
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				applier.ApplyRect(dst, src, r)
+			}
+		}()
+	}
+
+	for _, r := range tiles {
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+
+	return dst, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+// TestTilerTilesCoverBounds checks that tiles() covers every pixel of
+// bounds exactly once, including the non-trivial case where the image
+// dimensions aren't an even multiple of the tile size and the trailing
+// row/column of tiles has to be clipped.
+func TestTilerTilesCoverBounds(t *testing.T) {
+	bounds := image.Rect(0, 0, 17, 11)
+	tiler := NewTiler(5, 4)
+
+	covered := make(map[image.Point]int)
+	for _, r := range tiler.tiles(bounds) {
+		if !r.In(bounds) {
+			t.Fatalf("tile %v escapes bounds %v", r, bounds)
+		}
+		if r.Empty() {
+			t.Fatalf("tile %v is empty", r)
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				covered[image.Pt(x, y)]++
+			}
+		}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			n := covered[image.Pt(x, y)]
+			if n != 1 {
+				t.Fatalf("pixel (%d,%d) covered %d times, want exactly 1", x, y, n)
+			}
+		}
+	}
+}
+
+// TestTilerTilesExactMultiple checks the simpler case where the image
+// dimensions are an exact multiple of the tile size, so every tile is the
+// same full size and none need clipping.
+func TestTilerTilesExactMultiple(t *testing.T) {
+	bounds := image.Rect(0, 0, 10, 6)
+	tiler := NewTiler(5, 3)
+
+	tiles := tiler.tiles(bounds)
+	if len(tiles) != 4 {
+		t.Fatalf("len(tiles) = %d, want 4", len(tiles))
+	}
+	for _, r := range tiles {
+		if r.Dx() != 5 || r.Dy() != 3 {
+			t.Errorf("tile %v has size %dx%d, want 5x3", r, r.Dx(), r.Dy())
+		}
+	}
+}
+
+// TestTilerApplyFallsBackWithoutRectApplier checks that a Filter which
+// doesn't implement RectApplier is still run, via a single whole-image
+// Apply call, rather than silently dropped.
+func TestTilerApplyFallsBackWithoutRectApplier(t *testing.T) {
+	img := gradientImage(8, 8)
+	tiler := NewTiler(3, 3)
+
+	out, err := tiler.Apply(grayscaleFilterNoRect{}, img)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if out.Bounds() != img.Bounds() {
+		t.Fatalf("out bounds = %v, want %v", out.Bounds(), img.Bounds())
+	}
+}
+
+// grayscaleFilterNoRect wraps grayscaleFilter's Apply without its
+// ApplyRect, so it does not satisfy RectApplier.
+type grayscaleFilterNoRect struct{}
+
+func (grayscaleFilterNoRect) Apply(img image.Image) (image.Image, error) {
+	return grayscaleFilter{}.Apply(img)
+}